@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// cacheMeta records the RTR version metadata a quarter was fetched at, so a
+// cached entry can later be compared against the upstream index to decide
+// whether it is stale.
+type cacheMeta struct {
+	VersionId uint16
+	Published string
+}
+
+// quarterCacheInfo describes one cached quarter, as surfaced by
+// TransactionStore.List and the `cache status` command.
+type quarterCacheInfo struct {
+	Quarter string
+	Meta    cacheMeta
+	Count   int
+}
+
+// TransactionStore persists loaded transactions per quarter so repeated runs
+// don't need to re-hit data.rtr.at. Implementations must be safe for
+// concurrent use.
+type TransactionStore interface {
+	Get(quarter string) ([]transaction, cacheMeta, bool, error)
+	Put(quarter string, transactions []transaction, meta cacheMeta) error
+	Delete(quarter string) error
+	DeleteAll() error
+	List() ([]quarterCacheInfo, error)
+}
+
+// memoryStore is an in-memory TransactionStore, mainly useful for tests and
+// as a fallback when the on-disk cache directory can't be created. mutex
+// guards entries, since the load worker pool calls Put concurrently.
+type memoryStore struct {
+	mutex   sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	transactions []transaction
+	meta         cacheMeta
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (store *memoryStore) Get(quarter string) ([]transaction, cacheMeta, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	entry, exists := store.entries[quarter]
+	if !exists {
+		return nil, cacheMeta{}, false, nil
+	}
+	return entry.transactions, entry.meta, true, nil
+}
+
+func (store *memoryStore) Put(quarter string, transactions []transaction, meta cacheMeta) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.entries[quarter] = memoryEntry{transactions: transactions, meta: meta}
+	return nil
+}
+
+func (store *memoryStore) Delete(quarter string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.entries, quarter)
+	return nil
+}
+
+func (store *memoryStore) DeleteAll() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.entries = make(map[string]memoryEntry)
+	return nil
+}
+
+func (store *memoryStore) List() ([]quarterCacheInfo, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	infos := make([]quarterCacheInfo, 0, len(store.entries))
+	for quarter, entry := range store.entries {
+		infos = append(infos, quarterCacheInfo{Quarter: quarter, Meta: entry.meta, Count: len(entry.transactions)})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Quarter < infos[j].Quarter })
+	return infos, nil
+}
+
+// diskStore is a TransactionStore backed by per-quarter JSON files under a
+// cache directory, with a manifest.json recording the cache metadata. Writes
+// are atomic: the payload is written to a temporary file in the same
+// directory and then renamed into place, so a crash mid-write can never
+// leave a corrupt quarter file behind. mutex serializes every method, since
+// the load worker pool calls Put concurrently and the manifest's
+// read-modify-write would otherwise lose updates.
+type diskStore struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+func xdgCacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "rtr-cli"), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rtr-cli"), nil
+}
+
+func newDiskStore(dir string) (*diskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &diskStore{dir: dir}, nil
+}
+
+func (store *diskStore) manifestPath() string {
+	return filepath.Join(store.dir, "manifest.json")
+}
+
+func (store *diskStore) quarterPath(quarter string) string {
+	return filepath.Join(store.dir, quarter+".json")
+}
+
+func (store *diskStore) readManifest() (map[string]cacheMeta, error) {
+	manifest := make(map[string]cacheMeta)
+	body, err := os.ReadFile(store.manifestPath())
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (store *diskStore) writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(store.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// readQuarterFile reads and decodes a quarter's cached transactions, without
+// touching the manifest. Returns (nil, nil) if the file doesn't exist, same
+// as a manifest miss. Callers must hold store.mutex.
+func (store *diskStore) readQuarterFile(quarter string) ([]transaction, error) {
+	body, err := os.ReadFile(store.quarterPath(quarter))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var transactions []transaction
+	if err := json.Unmarshal(body, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (store *diskStore) Get(quarter string) ([]transaction, cacheMeta, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	manifest, err := store.readManifest()
+	if err != nil {
+		return nil, cacheMeta{}, false, err
+	}
+	meta, exists := manifest[quarter]
+	if !exists {
+		return nil, cacheMeta{}, false, nil
+	}
+	transactions, err := store.readQuarterFile(quarter)
+	if err != nil {
+		return nil, cacheMeta{}, false, err
+	}
+	if transactions == nil {
+		return nil, cacheMeta{}, false, nil
+	}
+	return transactions, meta, true, nil
+}
+
+func (store *diskStore) Put(quarter string, transactions []transaction, meta cacheMeta) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	body, err := json.Marshal(transactions)
+	if err != nil {
+		return err
+	}
+	if err := store.writeAtomic(store.quarterPath(quarter), body); err != nil {
+		return err
+	}
+	manifest, err := store.readManifest()
+	if err != nil {
+		return err
+	}
+	manifest[quarter] = meta
+	manifestBody, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return store.writeAtomic(store.manifestPath(), manifestBody)
+}
+
+func (store *diskStore) Delete(quarter string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	manifest, err := store.readManifest()
+	if err != nil {
+		return err
+	}
+	if _, exists := manifest[quarter]; !exists {
+		return nil
+	}
+	delete(manifest, quarter)
+	manifestBody, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := store.writeAtomic(store.manifestPath(), manifestBody); err != nil {
+		return err
+	}
+	if err := os.Remove(store.quarterPath(quarter)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (store *diskStore) DeleteAll() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	manifest, err := store.readManifest()
+	if err != nil {
+		return err
+	}
+	for quarter := range manifest {
+		if err := os.Remove(store.quarterPath(quarter)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Remove(store.manifestPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (store *diskStore) List() ([]quarterCacheInfo, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	manifest, err := store.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]quarterCacheInfo, 0, len(manifest))
+	for quarter, meta := range manifest {
+		count := 0
+		if transactions, err := store.readQuarterFile(quarter); err == nil {
+			count = len(transactions)
+		}
+		infos = append(infos, quarterCacheInfo{Quarter: quarter, Meta: meta, Count: count})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Quarter < infos[j].Quarter })
+	return infos, nil
+}
+
+// newDefaultStore opens the on-disk cache under the XDG cache dir, falling
+// back to an in-memory store (with a warning) if the directory can't be
+// created, e.g. on a read-only filesystem.
+func newDefaultStore() TransactionStore {
+	dir, err := xdgCacheDir()
+	if err != nil {
+		fmt.Printf("Warning: could not determine cache directory, caching disabled: %v\n", err)
+		return newMemoryStore()
+	}
+	store, err := newDiskStore(dir)
+	if err != nil {
+		fmt.Printf("Warning: could not open cache directory %s, caching disabled: %v\n", dir, err)
+		return newMemoryStore()
+	}
+	return store
+}
+
+type cacheClearFeature struct {
+	target string
+	store  TransactionStore
+}
+
+func (cacheClear *cacheClearFeature) Run() {
+	if cacheClear.target == "" {
+		fmt.Println("Wrong syntax for command cache clear, expected a quarter or 'all'")
+		return
+	}
+	if strings.ToLower(cacheClear.target) == "all" {
+		if err := cacheClear.store.DeleteAll(); err != nil {
+			fmt.Printf("Failed to clear cache: %v\n", err)
+			return
+		}
+		fmt.Println("Cleared cache for all quarters")
+		return
+	}
+	if !isValidQuarter(cacheClear.target) {
+		fmt.Printf("%s is not a valid quarter\n", cacheClear.target)
+		return
+	}
+	if err := cacheClear.store.Delete(cacheClear.target); err != nil {
+		fmt.Printf("Failed to clear cache for quarter %s: %v\n", cacheClear.target, err)
+		return
+	}
+	fmt.Printf("Cleared cache for quarter %s\n", cacheClear.target)
+}
+
+type cacheStatusFeature struct {
+	store TransactionStore
+}
+
+func (cacheStatus *cacheStatusFeature) Run() {
+	infos, err := cacheStatus.store.List()
+	if err != nil {
+		fmt.Printf("Failed to read cache status: %v\n", err)
+		return
+	}
+	if len(infos) == 0 {
+		fmt.Println("\tCache is empty")
+		return
+	}
+	for _, info := range infos {
+		fmt.Printf("\t%s - %d transactions (version %d, published %s)\n", info.Quarter, info.Count, info.Meta.VersionId, info.Meta.Published)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestMemoryStoreConcurrentPut guards against the concurrent-map-write panic
+// that a bare `store.entries[quarter] = ...` used to trigger under the load
+// worker pool's default --max-parallel.
+func TestMemoryStoreConcurrentPut(t *testing.T) {
+	store := newMemoryStore()
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		quarter := string(rune('A' + i%26))
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := store.Put(quarter, nil, cacheMeta{}); err != nil {
+				t.Errorf("Put: %v", err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+}
+
+// TestDiskStoreConcurrentPut guards against manifest entries being dropped
+// by an unsynchronized read-modify-write of manifest.json when the load
+// worker pool calls Put concurrently for several quarters.
+func TestDiskStoreConcurrentPut(t *testing.T) {
+	store, err := newDiskStore(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatalf("newDiskStore: %v", err)
+	}
+
+	const quarterCount = 8
+	var waitGroup sync.WaitGroup
+	for i := 0; i < quarterCount; i++ {
+		quarter := string(rune('A' + i))
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := store.Put(quarter, []transaction{{Rechtstraeger: quarter}}, cacheMeta{VersionId: 1}); err != nil {
+				t.Errorf("Put: %v", err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	infos, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(infos) != quarterCount {
+		t.Errorf("List returned %d quarters, want %d (manifest entries were lost)", len(infos), quarterCount)
+	}
+}
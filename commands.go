@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// init registers every built-in command against the package-level registry.
+// An out-of-tree command can extend the CLI the same way, from its own
+// init(), without editing buildFeature or helpFeature.
+func init() {
+	registry.register(&Command{
+		Name: "help",
+		Help: "... shows this message",
+		Build: func(args []string, ctx commandContext) feature {
+			return &helpFeature{}
+		},
+	})
+	registry.register(&Command{
+		Name:    "quit",
+		Aliases: []string{"exit", "q"},
+		Help:    "... quits the program",
+		Build: func(args []string, ctx commandContext) feature {
+			return &exitFeature{isRunning: ctx.isRunning}
+		},
+	})
+	registry.register(&Command{
+		Name:    "payers",
+		Aliases: []string{"ls"},
+		Help:    "... prints a sorted list of all payers",
+		Build: func(args []string, ctx commandContext) feature {
+			return &payersFeature{base: baseFeature{transactions: *ctx.data}}
+		},
+	})
+	registry.register(&Command{
+		Name: "recipients",
+		Help: "... prints a sorted list of all recipients",
+		Build: func(args []string, ctx commandContext) feature {
+			return &recipientsFeature{base: baseFeature{transactions: *ctx.data}}
+		},
+	})
+	registry.register(&Command{
+		Name: "quarters",
+		Help: "... prints a list of loaded quarters",
+		Build: func(args []string, ctx commandContext) feature {
+			return &quartersFeature{base: baseFeature{transactions: *ctx.data}}
+		},
+	})
+	registry.register(&Command{
+		Name: "top",
+		Help: "n <payers|recipients> <§2|§4|§31> ... prints the top n payers/recipients for given paragraph",
+		Build: func(args []string, ctx commandContext) feature {
+			return &topFeature{base: dataFeature{arguments: args, data: *ctx.data}}
+		},
+	})
+	registry.register(&Command{
+		Name: "search",
+		Help: "<payers|recipients> searchTerm ... prints a list of payers/recipients containing the given search term",
+		Build: func(args []string, ctx commandContext) feature {
+			return &searchFeature{base: dataFeature{arguments: args, data: *ctx.data}}
+		},
+	})
+	registry.register(&Command{
+		Name: "details",
+		Help: "<payers|recipients> organization ... prints a list of all payments payed or received by the given payer/recipient",
+		Build: func(args []string, ctx commandContext) feature {
+			return &detailsFeature{base: dataFeature{arguments: args, data: *ctx.data}}
+		},
+	})
+	registry.register(&Command{
+		Name: "load",
+		Help: "<quarter...> ... loads the given quarters, preferring cached data over data.rtr.at (also accepts ranges, wildcards, and 'all')",
+		Build: func(args []string, ctx commandContext) feature {
+			return &loadFeature{quarters: args, data: ctx.data, fetcher: ctx.fetcher, store: ctx.store, options: ctx.options}
+		},
+	})
+	registry.register(&Command{
+		Name: "reload",
+		Help: "<quarter...> ... re-loads the given quarters, bypassing the cache",
+		Build: func(args []string, ctx commandContext) feature {
+			return &reloadFeature{base: loadFeature{quarters: args, data: ctx.data, fetcher: ctx.fetcher, store: ctx.store, options: ctx.options}}
+		},
+	})
+	registry.register(&Command{
+		Name: "cache",
+		Help: "clear <quarter|all> | status ... manages the on-disk transaction cache",
+		Build: func(args []string, ctx commandContext) feature {
+			if len(args) < 1 {
+				fmt.Println("Wrong syntax for command cache, expected 'cache clear <quarter|all>' or 'cache status'")
+				return &noopFeature{silent: true}
+			}
+			switch strings.ToLower(args[0]) {
+			case "clear":
+				target := ""
+				if len(args) > 1 {
+					target = args[1]
+				}
+				return &cacheClearFeature{target: target, store: ctx.store}
+			case "status":
+				return &cacheStatusFeature{store: ctx.store}
+			default:
+				fmt.Printf("Unkown cache subcommand %s\n", args[0])
+				return &noopFeature{silent: true}
+			}
+		},
+	})
+}
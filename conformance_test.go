@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update regenerates the golden files instead of checking against them,
+// e.g. `go test -run TestConformance -update`.
+var update = flag.Bool("update", false, "update conformance golden files")
+
+const vectorsDir = "testdata/vectors"
+
+// fileFetcher is a Fetcher that serves a single recorded response.json from
+// disk, standing in for data.rtr.at in the conformance suite.
+type fileFetcher struct {
+	dir string
+}
+
+func (f fileFetcher) Fetch(ctx context.Context, quarter string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(f.dir, "response.json"))
+}
+
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+	run()
+	w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("failed to read captured stdout: %v", err)
+	}
+	return buf.String()
+}
+
+func checkGolden(t *testing.T, goldenPath, actual string) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatalf("failed to create golden dir: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, []byte(actual), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s (run with -update to create it): %v", goldenPath, err)
+	}
+	if actual != string(expected) {
+		t.Errorf("output for %s does not match golden file\n--- got ---\n%s\n--- want ---\n%s", goldenPath, actual, expected)
+	}
+}
+
+// TestConformance runs every feature against the recorded fixtures under
+// testdata/vectors and diffs the output against checked-in golden files.
+// It requires no network access; set SKIP_CONFORMANCE=1 to skip it entirely.
+func TestConformance(t *testing.T) {
+	if os.Getenv("SKIP_CONFORMANCE") == "1" {
+		t.Skip("SKIP_CONFORMANCE=1")
+	}
+
+	entries, err := os.ReadDir(vectorsDir)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", vectorsDir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		quarter := entry.Name()
+		t.Run(quarter, func(t *testing.T) {
+			fetcher := fileFetcher{dir: filepath.Join(vectorsDir, quarter)}
+			transactions, _, err := loadData(context.Background(), quarter, fetcher)
+			if err != nil {
+				t.Fatalf("loadData(%s) failed: %v", quarter, err)
+			}
+
+			cases := []struct {
+				name    string
+				feature formattedFeature
+			}{
+				{"payers", &payersFeature{base: baseFeature{transactions: transactions}}},
+				{"recipients", &recipientsFeature{base: baseFeature{transactions: transactions}}},
+				{"quarters", &quartersFeature{base: baseFeature{transactions: transactions}}},
+				{"search", searchFeature{base: dataFeature{arguments: []string{"payers", "media"}, data: transactions}}},
+				{"details", detailsFeature{base: dataFeature{arguments: []string{"payers", "Acme Media GmbH"}, data: transactions}}},
+				{"top", &topFeature{base: dataFeature{arguments: []string{"1", "payers", "2"}, data: transactions}}},
+			}
+
+			for _, c := range cases {
+				t.Run(c.name, func(t *testing.T) {
+					actual := captureStdout(t, func() { c.feature.Run() })
+					golden := filepath.Join(vectorsDir, quarter, "golden", c.name+".txt")
+					checkGolden(t, golden, actual)
+				})
+
+				t.Run(c.name+"_json", func(t *testing.T) {
+					var buf bytes.Buffer
+					if err := c.feature.RunFormatted(&buf, FormatJSON); err != nil {
+						t.Fatalf("RunFormatted(FormatJSON): %v", err)
+					}
+					golden := filepath.Join(vectorsDir, quarter, "golden", c.name+".json")
+					checkGolden(t, golden, buf.String())
+				})
+			}
+		})
+	}
+}
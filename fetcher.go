@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Fetcher retrieves the raw RTR API response body for a quarter. It exists
+// so tests (and anything else that wants to avoid hitting data.rtr.at) can
+// swap in a fixture-backed implementation without touching loadData. The
+// context carries the per-request timeout applied by loadQuarterWithRetry.
+type Fetcher interface {
+	Fetch(ctx context.Context, quarter string) ([]byte, error)
+}
+
+// IndexFetcher is implemented by Fetchers that can report the full set of
+// quarters published upstream. It's consulted to expand the "all" quarter
+// selector (see the quarters package), which can't be resolved from a spec
+// alone. Fixture-backed Fetchers used in tests are free to not implement it.
+type IndexFetcher interface {
+	FetchIndex(ctx context.Context) ([]string, error)
+}
+
+// rtrIndexResponse is the shape of the RTR distinct-values index endpoint,
+// which lists every quarter that has been published rather than the
+// transactions for one of them.
+type rtrIndexResponse struct {
+	Message string
+	Status  uint16
+	Data    []struct {
+		Quartal string
+	}
+}
+
+// httpFetcher is the production Fetcher, talking to the real RTR API. A 5xx
+// response is reported as a retryableError so the worker pool's backoff
+// loop knows it's worth retrying.
+type httpFetcher struct{}
+
+func (httpFetcher) Fetch(ctx context.Context, quarter string) ([]byte, error) {
+	url := fmt.Sprintf("https://data.rtr.at/api/v1/tables/MedKFTGBekanntgabe.json?quartal=%s&leermeldung=0&size=0", quarter)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return nil, &retryableError{err: fmt.Errorf("data.rtr.at returned %s for quarter %s", resp.Status, quarter)}
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// FetchIndex probes the RTR distinct-values index endpoint for the full
+// catalog of published quarters, so the "all" quarter selector can be
+// expanded without the caller having to know it in advance.
+func (httpFetcher) FetchIndex(ctx context.Context) ([]string, error) {
+	url := "https://data.rtr.at/api/v1/tables/MedKFTGBekanntgabe.json?distinct=quartal&size=0"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return nil, &retryableError{err: fmt.Errorf("data.rtr.at returned %s for the quarter index", resp.Status)}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var result rtrIndexResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	quarters := make([]string, 0, len(result.Data))
+	for _, entry := range result.Data {
+		quarters = append(quarters, entry.Quartal)
+	}
+	return quarters, nil
+}
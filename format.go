@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Format selects how a formattedFeature renders its result: the existing
+// human-readable tables, or machine-readable JSON for shell pipelines.
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSON
+)
+
+// parseFormat parses the --format flag value. An empty string defaults to
+// FormatText so the flag can be omitted.
+func parseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return FormatText, fmt.Errorf("unknown format %q, expected 'text' or 'json'", s)
+	}
+}
+
+// formattedFeature is implemented by features that can render their result
+// to an arbitrary writer in a chosen Format, for use in --script mode. Run()
+// keeps printing to stdout in FormatText for the interactive REPL.
+type formattedFeature interface {
+	feature
+	RunFormatted(w io.Writer, format Format) error
+}
+
+func uniqueSortedKeys(mapper func(transaction) string, transactions []transaction) []string {
+	set := make(map[string]struct{})
+	for _, transaction := range transactions {
+		s := mapper(transaction)
+		if _, exists := set[s]; !exists {
+			set[s] = struct{}{}
+		}
+	}
+	keys := make([]string, len(set))
+	i := 0
+	for k := range set {
+		keys[i] = k
+		i++
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessLower(keys[i], keys[j]) })
+	return keys
+}
+
+func writeUniqueResults(w io.Writer, format Format, mapper func(transaction) string, transactions []transaction) error {
+	keys := uniqueSortedKeys(mapper, transactions)
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(keys)
+	}
+	for _, s := range keys {
+		fmt.Fprintf(w, "\t%s\n", s)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lorenz-Possnig/microproject-go/quarters"
+)
+
+// LoadOptions configures loadMultipleData's worker pool and retry behavior.
+// Programmatic callers that don't care can use DefaultLoadOptions.
+type LoadOptions struct {
+	MaxParallel int
+	RetryMax    int
+	Timeout     time.Duration
+}
+
+// DefaultLoadOptions mirrors the CLI defaults (--max-parallel 4).
+func DefaultLoadOptions() LoadOptions {
+	return LoadOptions{MaxParallel: 4, RetryMax: 3, Timeout: 10 * time.Second}
+}
+
+// retryableError marks a Fetcher error as transient (worth retrying),
+// e.g. a 5xx response from data.rtr.at.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var retryable *retryableError
+	if errors.As(err, &retryable) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return false
+}
+
+// backoff returns an exponential backoff duration with jitter for the given
+// (zero-based) retry attempt, capped at 5s so a flaky endpoint doesn't stall
+// the whole load.
+func backoff(attempt int) time.Duration {
+	base := 200 * time.Millisecond * time.Duration(uint64(1)<<uint(attempt))
+	if base > 5*time.Second {
+		base = 5 * time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(base)/2 + 1))
+	return base/2 + jitter
+}
+
+// loadQuarterWithRetry loads a single quarter, retrying transient Fetcher
+// failures (5xx, timeouts, EOF) with exponential backoff. It consults store
+// first unless force is set, and back-fills store on a successful fetch.
+func loadQuarterWithRetry(ctx context.Context, quarter string, fetcher Fetcher, store TransactionStore, force bool, opts LoadOptions) ([]transaction, error) {
+	if !isValidQuarter(quarter) {
+		return nil, fmt.Errorf("%s is not a valid quarter", quarter)
+	}
+	if !force {
+		if transactions, _, ok, err := store.Get(quarter); err == nil && ok {
+			return transactions, nil
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= opts.RetryMax; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		transactions, version, err := loadData(attemptCtx, quarter, fetcher)
+		cancel()
+		if err == nil {
+			meta := cacheMeta{VersionId: version.Id, Published: version.Published}
+			if err := store.Put(quarter, transactions, meta); err != nil {
+				fmt.Printf("Warning: failed to cache quarter %s: %v\n", quarter, err)
+			}
+			return transactions, nil
+		}
+		lastErr = err
+		if !isRetryable(err) || attempt == opts.RetryMax {
+			break
+		}
+		select {
+		case <-time.After(backoff(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, fmt.Errorf("quarter %s: %w", quarter, lastErr)
+}
+
+// expandQuarterSpecs resolves the raw arguments of a `load`/`reload` command
+// (quarters, ranges, wildcards, or "all") into the sorted, deduplicated list
+// of concrete quarters to fetch. "all" is only resolved if fetcher is an
+// IndexFetcher, since the full published catalog can't otherwise be known.
+func expandQuarterSpecs(ctx context.Context, specs []string, fetcher Fetcher) ([]string, error) {
+	var known []string
+	for _, spec := range specs {
+		if strings.EqualFold(spec, "all") {
+			indexFetcher, ok := fetcher.(IndexFetcher)
+			if !ok {
+				return nil, fmt.Errorf("quarter selector \"all\" is not supported by this fetcher")
+			}
+			catalog, err := indexFetcher.FetchIndex(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch the published quarter catalog: %w", err)
+			}
+			known = catalog
+			break
+		}
+	}
+
+	seen := make(map[string]struct{})
+	result := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		expanded, err := quarters.Parse(spec, known)
+		if err != nil {
+			return nil, err
+		}
+		for _, quarter := range expanded {
+			if _, exists := seen[quarter]; exists {
+				continue
+			}
+			seen[quarter] = struct{}{}
+			result = append(result, quarter)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// loadMultipleDataWithOptions loads quarters through a worker pool bounded
+// to opts.MaxParallel concurrent fetches, printing a live "n/N quarters
+// loaded" progress indicator as they complete.
+func loadMultipleDataWithOptions(ctx context.Context, quarters []string, data *[]transaction, fetcher Fetcher, store TransactionStore, force bool, opts LoadOptions) error {
+	if len(quarters) == 0 {
+		return fmt.Errorf("at least one quarter to be loaded must be specified")
+	}
+	maxParallel := opts.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = 1
+	}
+
+	errs := make([]error, 0)
+	var errorsMutex sync.Mutex
+	var transactionMutex sync.Mutex
+	var progressMutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	sem := make(chan struct{}, maxParallel)
+
+	total := len(quarters)
+	completed := 0
+	waitGroup.Add(total)
+	for _, quarter := range quarters {
+		quarter := quarter
+		sem <- struct{}{}
+		go func() {
+			defer waitGroup.Done()
+			defer func() { <-sem }()
+
+			transactions, err := loadQuarterWithRetry(ctx, quarter, fetcher, store, force, opts)
+
+			progressMutex.Lock()
+			completed++
+			fmt.Printf("\r%d/%d quarters loaded", completed, total)
+			progressMutex.Unlock()
+
+			if err != nil {
+				errorsMutex.Lock()
+				errs = append(errs, err)
+				errorsMutex.Unlock()
+				return
+			}
+			transactionMutex.Lock()
+			defer transactionMutex.Unlock()
+			*data = append(*data, transactions...)
+		}()
+	}
+	waitGroup.Wait()
+	fmt.Println()
+
+	return errors.Join(errs...)
+}
+
+// loadMultipleData is loadMultipleDataWithOptions with DefaultLoadOptions,
+// kept for callers that don't need to tune concurrency or retries.
+func loadMultipleData(quarters []string, data *[]transaction, fetcher Fetcher, store TransactionStore, force bool) error {
+	return loadMultipleDataWithOptions(context.Background(), quarters, data, fetcher, store, force, DefaultLoadOptions())
+}
@@ -1,18 +1,16 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
-	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"math"
-	"net/http"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"unicode"
 	"unicode/utf8"
 )
@@ -42,25 +40,45 @@ type feature interface {
 	Run()
 }
 
+// noopFeature is returned for a command the registry doesn't recognize. If
+// attempted is set, Run suggests the nearest registered command name by
+// Levenshtein distance. silent suppresses the message entirely, for a known
+// command whose Build already reported its own, more specific error (e.g.
+// `cache` with a missing or unknown subcommand).
 type noopFeature struct {
+	attempted string
+	silent    bool
 }
 
 func (nop *noopFeature) Run() {
+	if nop.silent {
+		return
+	}
+	if nop.attempted != "" {
+		if suggestion, ok := registry.nearest(nop.attempted); ok {
+			fmt.Printf("Unkown Command %q, did you mean %q?\n", nop.attempted, suggestion)
+			return
+		}
+	}
 	fmt.Println("Unkown Command")
 }
 
 type helpFeature struct {
 }
 
+// Run prints one line per registered command, in registration order, so
+// adding a command to the registry is enough to document it here too. Help
+// already carries the "... description" (and any "<args>" before it), so the
+// line is just the name followed by Help, not a separately-inserted "...".
 func (help *helpFeature) Run() {
-	fmt.Println("\tquit | exit ... quits the program")
-	fmt.Println("\thelp ... shows this message")
-	fmt.Println("\tpayers ... prints a sorted list of all payers")
-	fmt.Println("\trecipients ... prints a sorted list of all recipients")
-	fmt.Println("\tquarters ... prints a list of loaded quarters")
-	fmt.Println("\ttop n <payers|recipients> <§2|§4|§31> ... prints the top n payers/recipients for given paragraph")
-	fmt.Println("\tsearch <payers|recipients> searchTerm ... prints a list of payers/recipients containing the given search term")
-	fmt.Println("\tdetails <payers|recipients> organization ... prints a list of all payments payed or received by the given payer/recipient")
+	for _, cmd := range registry.commands {
+		fmt.Printf("\t%s %s\n", cmd.displayName(), cmd.Help)
+	}
+	fmt.Println("")
+	fmt.Println("\tRun with --script \"command; command\" (or -e) to execute commands non-interactively and exit.")
+	fmt.Println("\tAdd --format json to emit machine-readable output for payers/recipients/quarters/top/search/details.")
+	fmt.Println("\tUse --max-parallel n to bound how many quarters load/reload fetches concurrently (default 4).")
+	fmt.Println("\tCommand history and Ctrl-R search persist across sessions.")
 }
 
 type exitFeature struct {
@@ -95,26 +113,6 @@ func lessLower(sa, sb string) bool {
 	}
 }
 
-func printUniqueResults(mapper func(transaction) string, transactions []transaction) {
-	set := make(map[string]struct{})
-	for _, transaction := range transactions {
-		s := mapper(transaction)
-		if _, exists := set[s]; !exists {
-			set[s] = struct{}{}
-		}
-	}
-	keys := make([]string, len(set))
-	i := 0
-	for k := range set {
-		keys[i] = k
-		i++
-	}
-	sort.Slice(keys, func(i, j int) bool { return lessLower(keys[i], keys[j]) })
-	for _, s := range keys {
-		fmt.Printf("\t%s\n", s)
-	}
-}
-
 type baseFeature struct {
 	transactions []transaction
 }
@@ -130,7 +128,11 @@ func getMedieninhaber(t transaction) string { return t.Medieninhaber }
 func getQuartal(t transaction) string { return t.Quartal }
 
 func (payers *payersFeature) Run() {
-	printUniqueResults(getRechtstraeger, payers.base.transactions)
+	payers.RunFormatted(os.Stdout, FormatText)
+}
+
+func (payers *payersFeature) RunFormatted(w io.Writer, format Format) error {
+	return writeUniqueResults(w, format, getRechtstraeger, payers.base.transactions)
 }
 
 type recipientsFeature struct {
@@ -138,7 +140,11 @@ type recipientsFeature struct {
 }
 
 func (recipients *recipientsFeature) Run() {
-	printUniqueResults(getMedieninhaber, recipients.base.transactions)
+	recipients.RunFormatted(os.Stdout, FormatText)
+}
+
+func (recipients *recipientsFeature) RunFormatted(w io.Writer, format Format) error {
+	return writeUniqueResults(w, format, getMedieninhaber, recipients.base.transactions)
 }
 
 type quartersFeature struct {
@@ -146,7 +152,11 @@ type quartersFeature struct {
 }
 
 func (quarters *quartersFeature) Run() {
-	printUniqueResults(getQuartal, quarters.base.transactions)
+	quarters.RunFormatted(os.Stdout, FormatText)
+}
+
+func (quarters *quartersFeature) RunFormatted(w io.Writer, format Format) error {
+	return writeUniqueResults(w, format, getQuartal, quarters.base.transactions)
 }
 
 func isValidQuarter(quarter string) bool {
@@ -161,64 +171,40 @@ func isValidQuarter(quarter string) bool {
 	return true
 }
 
-func loadData(quarter string) ([]transaction, error) {
+func loadData(ctx context.Context, quarter string, fetcher Fetcher) ([]transaction, rtrVersion, error) {
 	if !isValidQuarter(quarter) {
-		return nil, fmt.Errorf("%s is not a valid quarter", quarter)
+		return nil, rtrVersion{}, fmt.Errorf("%s is not a valid quarter", quarter)
 	}
 	fmt.Printf("Loading data for quarter %s\n", quarter)
-	resp, err := http.Get(fmt.Sprintf("https://data.rtr.at/api/v1/tables/MedKFTGBekanntgabe.json?quartal=%s&leermeldung=0&size=0", quarter))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	body, err := fetcher.Fetch(ctx, quarter)
 	if err != nil {
-		return nil, err
+		return nil, rtrVersion{}, err
 	}
 	var result rtrResponse
 	err = json.Unmarshal(body, &result)
 	if err != nil {
-		return nil, err
-	}
-	return result.Data, nil
-}
-
-func loadMultipleData(quarters []string, data *[]transaction) error {
-	if len(quarters) == 0 {
-		return fmt.Errorf("at least one quarter to be loaded must be specified")
-	}
-	errs := make([]error, 0)
-	var errorsMutex sync.Mutex
-	var transactionMutex sync.Mutex
-	var waitGroup sync.WaitGroup
-	waitGroup.Add(len(quarters))
-	for _, quarter := range quarters {
-		go func() {
-			defer waitGroup.Done()
-			transactions, err := loadData(quarter)
-			if err != nil {
-				errorsMutex.Lock()
-				errs = append(errs, err)
-				errorsMutex.Unlock()
-				return
-			}
-			transactionMutex.Lock()
-			defer transactionMutex.Unlock()
-			*data = append(*data, transactions...)
-		}()
+		return nil, rtrVersion{}, err
 	}
-	waitGroup.Wait()
-
-	return errors.Join(errs...)
+	return result.Data, result.Version, nil
 }
 
 type loadFeature struct {
 	quarters []string
 	data     *[]transaction
+	fetcher  Fetcher
+	store    TransactionStore
+	force    bool
+	options  LoadOptions
 }
 
 func (load *loadFeature) Run() {
-	err := loadMultipleData(load.quarters, load.data)
+	ctx := context.Background()
+	resolved, err := expandQuarterSpecs(ctx, load.quarters, load.fetcher)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		return
+	}
+	err = loadMultipleDataWithOptions(ctx, resolved, load.data, load.fetcher, load.store, load.force, load.options)
 	if err != nil {
 		fmt.Printf("%v\n", err)
 	}
@@ -229,7 +215,8 @@ type reloadFeature struct {
 }
 
 func (reload *reloadFeature) Run() {
-	clear(*reload.base.data)
+	*reload.base.data = (*reload.base.data)[:0]
+	reload.base.force = true
 	reload.base.Run()
 }
 
@@ -248,14 +235,18 @@ type stringFloatPair struct {
 }
 
 func (top *topFeature) Run() {
+	top.RunFormatted(os.Stdout, FormatText)
+}
+
+func (top *topFeature) RunFormatted(w io.Writer, format Format) error {
 	if len(top.base.arguments) != 3 {
-		fmt.Println("Wrong syntax for command top")
-		return
+		fmt.Fprintln(w, "Wrong syntax for command top")
+		return nil
 	}
 	amount, err := strconv.ParseUint(top.base.arguments[0], 10, 8)
 	if err != nil {
-		fmt.Printf("Value %s for parameter 1 is invalid: %v\n", top.base.arguments[0], err)
-		return
+		fmt.Fprintf(w, "Value %s for parameter 1 is invalid: %v\n", top.base.arguments[0], err)
+		return nil
 	}
 
 	var mapper func(transaction) string
@@ -265,22 +256,22 @@ func (top *topFeature) Run() {
 	case "recipients":
 		mapper = getMedieninhaber
 	default:
-		fmt.Printf("Value %s for parameter 2 is invalid: Allowed values are ['payers', 'recipients']\n", top.base.arguments[1])
-		return
+		fmt.Fprintf(w, "Value %s for parameter 2 is invalid: Allowed values are ['payers', 'recipients']\n", top.base.arguments[1])
+		return nil
 	}
 
 	bekanntgabe, err := strconv.ParseUint(top.base.arguments[2], 10, 8)
 	if err != nil {
-		fmt.Printf("Value %s for parameter 3 is invalid: %v\n", top.base.arguments[2], err)
-		return
+		fmt.Fprintf(w, "Value %s for parameter 3 is invalid: %v\n", top.base.arguments[2], err)
+		return nil
 	}
 	if amount <= 0 {
-		fmt.Printf("%d is not a valid input for parameter amount\n", amount)
-		return
+		fmt.Fprintf(w, "%d is not a valid input for parameter amount\n", amount)
+		return nil
 	}
 	if bekanntgabe != 2 && bekanntgabe != 4 && bekanntgabe != 31 {
-		fmt.Printf("%d is not a valid input for parameter bekanntgabe\n", bekanntgabe)
-		return
+		fmt.Fprintf(w, "%d is not a valid input for parameter bekanntgabe\n", bekanntgabe)
+		return nil
 	}
 	m := make(map[string]float64)
 	for _, transaction := range top.base.data {
@@ -299,6 +290,19 @@ func (top *topFeature) Run() {
 		slice = append(slice, stringFloatPair{str: key, value: value})
 	}
 	sort.Slice(slice, func(i, j int) bool { return slice[i].value > slice[j].value })
+
+	if format == FormatJSON {
+		type topEntry struct {
+			Name string  `json:"name"`
+			Euro float64 `json:"euro"`
+		}
+		entries := make([]topEntry, 0, amount)
+		for _, pair := range slice[0:amount] {
+			entries = append(entries, topEntry{Name: pair.str, Euro: pair.value})
+		}
+		return json.NewEncoder(w).Encode(entries)
+	}
+
 	maxFloatWidth := int(math.Log10(slice[0].value)) + 1
 	maxStringLength := 0
 	for _, pair := range slice {
@@ -309,8 +313,9 @@ func (top *topFeature) Run() {
 	}
 
 	for idx, pair := range slice[0:amount] {
-		fmt.Printf(("\t%3d. %" + strconv.Itoa(maxStringLength) + "s - %" + strconv.Itoa(maxFloatWidth) + ".2f€\n"), idx+1, pair.str, pair.value)
+		fmt.Fprintf(w, ("\t%3d. %" + strconv.Itoa(maxStringLength) + "s - %" + strconv.Itoa(maxFloatWidth) + ".2f€\n"), idx+1, pair.str, pair.value)
 	}
+	return nil
 }
 
 type searchFeature struct {
@@ -318,9 +323,13 @@ type searchFeature struct {
 }
 
 func (search searchFeature) Run() {
+	search.RunFormatted(os.Stdout, FormatText)
+}
+
+func (search searchFeature) RunFormatted(w io.Writer, format Format) error {
 	if len(search.base.arguments) < 2 {
-		fmt.Println("At least two parameters need to be provided")
-		return
+		fmt.Fprintln(w, "At least two parameters need to be provided")
+		return nil
 	}
 	por := search.base.arguments[0]
 	var mapper func(transaction) string
@@ -330,7 +339,7 @@ func (search searchFeature) Run() {
 	case "recipients":
 		mapper = getMedieninhaber
 	default:
-		fmt.Printf("Value %s for parameter 2 is invalid: Allowed values are ['payers', 'recipients']\n", search.base.arguments[0])
+		fmt.Fprintf(w, "Value %s for parameter 2 is invalid: Allowed values are ['payers', 'recipients']\n", search.base.arguments[0])
 	}
 	searchTerm := strings.ToLower(strings.Join(search.base.arguments[1:], " "))
 	set := make(map[string]struct{})
@@ -345,16 +354,21 @@ func (search searchFeature) Run() {
 		slice = append(slice, str)
 	}
 	sort.Slice(slice, func(i, j int) bool { return lessLower(slice[i], slice[j]) })
+
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(slice)
+	}
 	for idx, str := range slice {
-		fmt.Printf("\t%d. %s\n", idx+1, str)
+		fmt.Fprintf(w, "\t%d. %s\n", idx+1, str)
 	}
+	return nil
 }
 
 type detailsFeature struct {
 	base dataFeature
 }
 
-func printAll(m map[string]float64) {
+func writeAll(w io.Writer, m map[string]float64) {
 	length := len(m)
 
 	if length == 0 {
@@ -376,14 +390,18 @@ func printAll(m map[string]float64) {
 	}
 
 	for idx, pair := range slice {
-		fmt.Printf(("\t%3d. %" + strconv.Itoa(maxStringLength) + "s - %" + strconv.Itoa(maxFloatWidth) + ".2f€\n"), idx+1, pair.str, pair.value)
+		fmt.Fprintf(w, ("\t%3d. %" + strconv.Itoa(maxStringLength) + "s - %" + strconv.Itoa(maxFloatWidth) + ".2f€\n"), idx+1, pair.str, pair.value)
 	}
 }
 
 func (details detailsFeature) Run() {
+	details.RunFormatted(os.Stdout, FormatText)
+}
+
+func (details detailsFeature) RunFormatted(w io.Writer, format Format) error {
 	if len(details.base.arguments) < 1 {
-		fmt.Println("At least one parameter needs to be provided")
-		return
+		fmt.Fprintln(w, "At least one parameter needs to be provided")
+		return nil
 	}
 	por := details.base.arguments[0]
 	var mapper func(transaction) string
@@ -396,8 +414,8 @@ func (details detailsFeature) Run() {
 		mapper = getMedieninhaber
 		reverseMapper = getRechtstraeger
 	default:
-		fmt.Printf("Value %s for parameter 2 is invalid: Allowed values are ['payers', 'recipients']\n", details.base.arguments[0])
-		return
+		fmt.Fprintf(w, "Value %s for parameter 2 is invalid: Allowed values are ['payers', 'recipients']\n", details.base.arguments[0])
+		return nil
 	}
 	organization := strings.Join(details.base.arguments[1:], " ")
 	m := make(map[uint8]map[string]float64)
@@ -410,67 +428,92 @@ func (details detailsFeature) Run() {
 			a[reverseMapper(transaction)] += float64(transaction.Euro)
 		}
 	}
-	fmt.Println("\tPayments §2:")
-	printAll(m[2])
-	fmt.Println("\tPayments §4:")
-	printAll(m[4])
-	fmt.Println("\tPayments §31:")
-	printAll(m[31])
+
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(m)
+	}
+
+	fmt.Fprintln(w, "\tPayments §2:")
+	writeAll(w, m[2])
+	fmt.Fprintln(w, "\tPayments §4:")
+	writeAll(w, m[4])
+	fmt.Fprintln(w, "\tPayments §31:")
+	writeAll(w, m[31])
+	return nil
+}
+
+// buildFeature parses one command line (as already split on whitespace) into
+// the feature that should handle it, sharing the same dispatch for both the
+// interactive REPL and --script mode. Commands themselves are looked up in
+// registry rather than hard-coded here; see commands.go for registration.
+func buildFeature(cli []string, isRunning *bool, data *[]transaction, fetcher Fetcher, store TransactionStore, options LoadOptions) feature {
+	if len(cli) == 0 {
+		return &noopFeature{}
+	}
+	cmd, ok := registry.lookup(cli[0])
+	if !ok {
+		return &noopFeature{attempted: cli[0]}
+	}
+	ctx := commandContext{isRunning: isRunning, data: data, fetcher: fetcher, store: store, options: options}
+	return cmd.Build(cli[1:], ctx)
+}
+
+// runFeature executes f, preferring RunFormatted when f supports it so
+// --script/--format json output stays machine-readable.
+func runFeature(f feature, format Format) {
+	if formatted, ok := f.(formattedFeature); ok {
+		if err := formatted.RunFormatted(os.Stdout, format); err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		return
+	}
+	f.Run()
 }
 
 func main() {
+	scriptFlag := flag.String("script", "", "run semicolon-separated commands non-interactively and exit")
+	flag.StringVar(scriptFlag, "e", "", "alias for -script")
+	formatFlag := flag.String("format", "text", "output format for non-interactive commands: text|json")
+	maxParallelFlag := flag.Int("max-parallel", DefaultLoadOptions().MaxParallel, "maximum number of quarters to load concurrently")
+	flag.Parse()
 
-	args := os.Args[1:]
+	format, err := parseFormat(*formatFlag)
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(1)
+	}
+
+	options := DefaultLoadOptions()
+	options.MaxParallel = *maxParallelFlag
 
+	args := flag.Args()
+
+	store := newDefaultStore()
+	fetcher := httpFetcher{}
 	data := make([]transaction, 0)
-	err := loadMultipleData(args, &data)
+	resolved, err := expandQuarterSpecs(context.Background(), args, fetcher)
 	if err != nil {
 		fmt.Printf("An error occured while loading data: %v\n", err)
+	} else if err = loadMultipleDataWithOptions(context.Background(), resolved, &data, fetcher, store, false, options); err != nil {
+		fmt.Printf("An error occured while loading data: %v\n", err)
 	}
 
-	fmt.Println("Welcome to the Go-Microproject!")
-	reader := bufio.NewReader(os.Stdin)
 	isRunning := true
-	for isRunning {
-		fmt.Println("Please enter a command or type 'help' for more information")
-		fmt.Print("> ")
-		text, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Printf("An error occured: %v\n", err)
-			continue
-		}
-		text = strings.Trim(text, " \t\n")
-		cli := strings.Split(text, " ")
-		if len(cli) == 0 {
-			continue
-		}
-		command := strings.ToLower(cli[0])
-		var feature feature = &noopFeature{}
-		switch command {
-		case "help":
-			feature = &helpFeature{}
-		case "exit":
-			fallthrough
-		case "quit":
-			feature = &exitFeature{isRunning: &isRunning}
-		case "payers":
-			feature = &payersFeature{base: baseFeature{transactions: data}}
-		case "recipients":
-			feature = &recipientsFeature{base: baseFeature{transactions: data}}
-		case "quarters":
-			feature = &quartersFeature{base: baseFeature{transactions: data}}
-		case "load":
-			feature = &loadFeature{quarters: cli[1:], data: &data}
-		case "reload":
-			feature = &reloadFeature{base: loadFeature{quarters: cli[1:], data: &data}}
-		case "top":
-			feature = &topFeature{base: dataFeature{arguments: cli[1:], data: data}}
-		case "search":
-			feature = &searchFeature{base: dataFeature{arguments: cli[1:], data: data}}
-		case "details":
-			feature = &detailsFeature{base: dataFeature{arguments: cli[1:], data: data}}
+
+	if *scriptFlag != "" {
+		for _, command := range strings.Split(*scriptFlag, ";") {
+			command = strings.Trim(command, " \t\n")
+			if command == "" {
+				continue
+			}
+			cli := strings.Split(command, " ")
+			runFeature(buildFeature(cli, &isRunning, &data, fetcher, store, options), format)
+			if !isRunning {
+				break
+			}
 		}
-		feature.Run()
+		return
 	}
-	fmt.Println("Bye!")
+
+	runREPL(&data, fetcher, store, options)
 }
@@ -0,0 +1,112 @@
+// Package quarters parses the quarter selectors accepted by the `load` and
+// `reload` commands: a single quarter ("20241"), an inclusive range
+// ("20231..20244"), a year wildcard ("2024*"), or the literal "all".
+package quarters
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parse expands spec into the sorted list of concrete quarters (each
+// "YYYYQ", Q in 1..4) it denotes. known is only consulted for the literal
+// "all" selector, since the full published catalog isn't knowable from the
+// spec alone; callers that don't support "all" can pass nil and will get
+// ErrAllUnavailable if a caller ever passes it.
+func Parse(spec string, known []string) ([]string, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("%q is not a valid quarter selector", spec)
+	}
+
+	if strings.EqualFold(spec, "all") {
+		if len(known) == 0 {
+			return nil, ErrAllUnavailable
+		}
+		result := append([]string{}, known...)
+		sort.Strings(result)
+		return result, nil
+	}
+
+	if strings.HasSuffix(spec, "*") {
+		return parseWildcard(spec)
+	}
+
+	if start, end, ok := strings.Cut(spec, ".."); ok {
+		return parseRange(spec, start, end)
+	}
+
+	if !isQuarter(spec) {
+		return nil, fmt.Errorf("%q is not a valid quarter, expected format YYYYQ (e.g. 20241)", spec)
+	}
+	return []string{spec}, nil
+}
+
+// ErrAllUnavailable is returned by Parse("all", nil) — the caller must
+// supply the known published catalog (e.g. by probing the RTR index
+// endpoint) before "all" can be expanded.
+var ErrAllUnavailable = fmt.Errorf("the full quarter catalog is not known; 'all' cannot be expanded")
+
+func isQuarter(s string) bool {
+	if len(s) != 5 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s[4] != '0'
+}
+
+func parseWildcard(spec string) ([]string, error) {
+	prefix := strings.TrimSuffix(spec, "*")
+	if len(prefix) != 4 {
+		return nil, fmt.Errorf("%q is not a valid wildcard, expected a 4-digit year followed by '*' (e.g. 2024*)", spec)
+	}
+	for _, r := range prefix {
+		if r < '0' || r > '9' {
+			return nil, fmt.Errorf("%q is not a valid wildcard, expected a 4-digit year followed by '*' (e.g. 2024*)", spec)
+		}
+	}
+	quarters := make([]string, 0, 4)
+	for q := 1; q <= 4; q++ {
+		quarters = append(quarters, fmt.Sprintf("%s%d", prefix, q))
+	}
+	return quarters, nil
+}
+
+func parseRange(spec, start, end string) ([]string, error) {
+	if !isQuarter(start) {
+		return nil, fmt.Errorf("%q in range %q is not a valid quarter, expected format YYYYQ", start, spec)
+	}
+	if !isQuarter(end) {
+		return nil, fmt.Errorf("%q in range %q is not a valid quarter, expected format YYYYQ", end, spec)
+	}
+	startIdx := quarterIndex(start)
+	endIdx := quarterIndex(end)
+	if startIdx > endIdx {
+		return nil, fmt.Errorf("range %q is empty: %q comes after %q", spec, start, end)
+	}
+	result := make([]string, 0, endIdx-startIdx+1)
+	for idx := startIdx; idx <= endIdx; idx++ {
+		result = append(result, formatQuarterIndex(idx))
+	}
+	return result, nil
+}
+
+// quarterIndex maps "YYYYQ" to a single increasing integer (year*4+quarter)
+// so ranges can be enumerated by simple integer arithmetic, wrapping over
+// year boundaries.
+func quarterIndex(q string) int {
+	year, _ := strconv.Atoi(q[:4])
+	quarter, _ := strconv.Atoi(q[4:5])
+	return year*4 + quarter
+}
+
+func formatQuarterIndex(idx int) string {
+	year := (idx - 1) / 4
+	quarter := idx - year*4
+	return fmt.Sprintf("%04d%d", year, quarter)
+}
@@ -0,0 +1,132 @@
+package quarters
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSingleQuarter(t *testing.T) {
+	got, err := Parse("20241", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"20241"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(20241) = %v, want %v", got, want)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	got, err := Parse("20231..20244", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"20231", "20232", "20233", "20234", "20241", "20242", "20243", "20244"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(20231..20244) = %v, want %v", got, want)
+	}
+}
+
+func TestParseRangeAcrossYearBoundary(t *testing.T) {
+	got, err := Parse("20234..20241", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"20234", "20241"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(20234..20241) = %v, want %v", got, want)
+	}
+}
+
+func TestParseRangeEmpty(t *testing.T) {
+	_, err := Parse("20244..20231", nil)
+	if err == nil {
+		t.Fatal("expected an error for an empty range")
+	}
+	if !contains(err.Error(), "20244..20231") {
+		t.Errorf("error %q does not name the offending spec", err.Error())
+	}
+}
+
+func TestParseRangeBadToken(t *testing.T) {
+	_, err := Parse("2023x..20244", nil)
+	if err == nil {
+		t.Fatal("expected an error for a malformed range start")
+	}
+	if !contains(err.Error(), "2023x") {
+		t.Errorf("error %q does not name the offending token", err.Error())
+	}
+}
+
+func TestParseWildcard(t *testing.T) {
+	got, err := Parse("2024*", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"20241", "20242", "20243", "20244"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(2024*) = %v, want %v", got, want)
+	}
+}
+
+func TestParseWildcardBadPrefix(t *testing.T) {
+	_, err := Parse("20x4*", nil)
+	if err == nil {
+		t.Fatal("expected an error for a malformed wildcard")
+	}
+	if !contains(err.Error(), "20x4*") {
+		t.Errorf("error %q does not name the offending spec", err.Error())
+	}
+}
+
+func TestParseAllWithKnownCatalog(t *testing.T) {
+	known := []string{"20242", "20241", "20243"}
+	got, err := Parse("all", known)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"20241", "20242", "20243"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(all) = %v, want %v", got, want)
+	}
+}
+
+func TestParseAllCaseInsensitive(t *testing.T) {
+	known := []string{"20241"}
+	if _, err := Parse("ALL", known); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseAllWithoutKnownCatalog(t *testing.T) {
+	_, err := Parse("all", nil)
+	if err != ErrAllUnavailable {
+		t.Errorf("Parse(all, nil) error = %v, want ErrAllUnavailable", err)
+	}
+}
+
+func TestParseInvalidQuarter(t *testing.T) {
+	_, err := Parse("not-a-quarter", nil)
+	if err == nil {
+		t.Fatal("expected an error for an invalid quarter")
+	}
+	if !contains(err.Error(), "not-a-quarter") {
+		t.Errorf("error %q does not name the offending token", err.Error())
+	}
+}
+
+func TestParseEmptySpec(t *testing.T) {
+	_, err := Parse("", nil)
+	if err == nil {
+		t.Fatal("expected an error for an empty spec")
+	}
+}
+
+func contains(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
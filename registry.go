@@ -0,0 +1,117 @@
+package main
+
+import "strings"
+
+// commandContext bundles the state a Command.Build needs to construct its
+// feature: the loaded transactions, the fetcher/store backing load/reload,
+// and the REPL's isRunning flag for exit/quit.
+type commandContext struct {
+	isRunning *bool
+	data      *[]transaction
+	fetcher   Fetcher
+	store     TransactionStore
+	options   LoadOptions
+}
+
+// Command describes one registered CLI command. Build constructs the
+// feature that runs it from the command's own arguments (cli with the
+// command name itself already stripped) and the shared commandContext.
+type Command struct {
+	Name    string
+	Aliases []string
+	Help    string
+	Build   func(args []string, ctx commandContext) feature
+}
+
+// displayName renders Name and any Aliases the way helpFeature prints them,
+// e.g. "quit | exit".
+func (c *Command) displayName() string {
+	return strings.Join(append([]string{c.Name}, c.Aliases...), " | ")
+}
+
+// commandRegistry holds every registered Command, keyed by name and alias
+// for dispatch, and in registration order for helpFeature to iterate. An
+// out-of-tree command registers itself against the package-level registry
+// from its own init(), without needing to touch buildFeature or helpFeature.
+type commandRegistry struct {
+	commands []*Command
+	byName   map[string]*Command
+}
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{byName: make(map[string]*Command)}
+}
+
+// register adds cmd under its name and all its aliases. Registering again
+// under a name/alias already taken replaces the earlier Command, so an
+// out-of-tree package can deliberately override a built-in if it needs to.
+func (r *commandRegistry) register(cmd *Command) {
+	r.commands = append(r.commands, cmd)
+	r.byName[strings.ToLower(cmd.Name)] = cmd
+	for _, alias := range cmd.Aliases {
+		r.byName[strings.ToLower(alias)] = cmd
+	}
+}
+
+func (r *commandRegistry) lookup(name string) (*Command, bool) {
+	cmd, ok := r.byName[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// nearest returns the registered command name closest (by Levenshtein
+// distance) to attempted, for noopFeature's "did you mean" suggestion. ok
+// is false if nothing is within a reasonable edit distance.
+func (r *commandRegistry) nearest(attempted string) (string, bool) {
+	attempted = strings.ToLower(attempted)
+	best := ""
+	bestDistance := -1
+	for _, cmd := range r.commands {
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			d := levenshtein(attempted, strings.ToLower(name))
+			if bestDistance == -1 || d < bestDistance {
+				bestDistance = d
+				best = name
+			}
+		}
+	}
+	if bestDistance == -1 || bestDistance > len(attempted)/2+1 {
+		return "", false
+	}
+	return best, true
+}
+
+// registry is the package-level CommandRegistry every built-in command
+// registers itself against in its init() (see commands.go).
+var registry = newCommandRegistry()
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	curr := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		curr[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(br)]
+}
+
+func minInt(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// historyFile is where the REPL persists command history across runs, via
+// readline's own load/save. ~/.rtr_history is used by convention; if the
+// home directory can't be determined, history is kept in the working
+// directory instead of disabling it outright.
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".rtr_history"
+	}
+	return filepath.Join(home, ".rtr_history")
+}
+
+// newCompleter builds the REPL's tab-completion tree from the registry:
+// every registered command and alias, plus hand-written subcommand
+// completions (payers/recipients, the §2/§4/§31 paragraphs for `top`) for
+// the handful of commands that take them, and — most usefully — the
+// organization names present in the currently loaded data for
+// `details <payers|recipients> <TAB>`.
+func newCompleter(data *[]transaction) *readline.PrefixCompleter {
+	orgNames := func(mapper func(transaction) string) func(string) []string {
+		return func(string) []string { return uniqueSortedKeys(mapper, *data) }
+	}
+	subcommands := map[string][]readline.PrefixCompleterInterface{
+		"cache": {
+			readline.PcItem("clear", readline.PcItem("all")),
+			readline.PcItem("status"),
+		},
+		"top": {
+			readline.PcItem("payers", readline.PcItem("2"), readline.PcItem("4"), readline.PcItem("31")),
+			readline.PcItem("recipients", readline.PcItem("2"), readline.PcItem("4"), readline.PcItem("31")),
+		},
+		"search": {
+			readline.PcItem("payers"),
+			readline.PcItem("recipients"),
+		},
+		"details": {
+			readline.PcItem("payers", readline.PcItemDynamic(orgNames(getRechtstraeger))),
+			readline.PcItem("recipients", readline.PcItemDynamic(orgNames(getMedieninhaber))),
+		},
+	}
+
+	items := make([]readline.PrefixCompleterInterface, 0, len(registry.commands))
+	for _, cmd := range registry.commands {
+		children := subcommands[cmd.Name]
+		items = append(items, readline.PcItem(cmd.Name, children...))
+		for _, alias := range cmd.Aliases {
+			items = append(items, readline.PcItem(alias, children...))
+		}
+	}
+	return readline.NewPrefixCompleter(items...)
+}
+
+// runREPL drives the interactive loop with persistent history, Ctrl-R
+// reverse search, and context-aware tab completion (all provided by
+// chzyer/readline), handing each entered line to buildFeature/Run the same
+// way --script mode hands lines to buildFeature/RunFormatted.
+func runREPL(data *[]transaction, fetcher Fetcher, store TransactionStore, options LoadOptions) {
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          "> ",
+		HistoryFile:     historyFile(),
+		AutoComplete:    newCompleter(data),
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		fmt.Printf("Failed to start the REPL, falling back is not supported: %v\n", err)
+		return
+	}
+	defer rl.Close()
+
+	fmt.Println("Welcome to the Go-Microproject!")
+	isRunning := true
+	for isRunning {
+		fmt.Println("Please enter a command or type 'help' for more information")
+		text, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		}
+		if err != nil {
+			break
+		}
+		text = strings.Trim(text, " \t\n")
+		if text == "" {
+			continue
+		}
+		cli := strings.Split(text, " ")
+		buildFeature(cli, &isRunning, data, fetcher, store, options).Run()
+	}
+	fmt.Println("Bye!")
+}